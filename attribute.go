@@ -1,9 +1,13 @@
 package radius
 
 // Attribute is a RADIUS attribute, which is part of a RADIUS packet.
+//
+// If VendorID is non-zero, the attribute is a vendor attribute carried inside
+// a Vendor-Specific attribute, and Type is its vendor type.
 type Attribute struct {
-	Type  byte
-	Value interface{}
+	VendorID uint32
+	Type     byte
+	Value    interface{}
 }
 
 // AttributeCodec defines how an Attribute is encoded and decoded to and from
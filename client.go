@@ -1,7 +1,7 @@
 package radius
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"net"
 	"strconv"
@@ -10,12 +10,21 @@ import (
 
 // Some commonly used attribute IDs
 const (
-	AttrFramedIPAddress  = 8
-	AttrVendorSpecific   = 26
-	AttrCallingStationID = 31
-	AttrAcctSessionID    = 44
+	AttrUserName             = 1
+	AttrUserPassword         = 2
+	AttrCHAPPassword         = 3
+	AttrFramedIPAddress      = 8
+	AttrVendorSpecific       = 26
+	AttrCallingStationID     = 31
+	AttrAcctStatusType       = 40
+	AttrAcctSessionID        = 44
+	AttrMessageAuthenticator = 80
 )
 
+// Per-attempt timeout used by Request and its vendor wrappers when
+// Client.Timeout is zero
+const defaultRequestTimeout = 5 * time.Second
+
 // Default CoA ports
 const (
 	coaPortCisco    = 1700
@@ -42,7 +51,10 @@ type Client struct {
 	// Local address to use for outgoing connections (can be nil)
 	LocalAddr *net.UDPAddr
 
-	// Timeout and retry count
+	// Per-attempt timeout and number of attempts. If Timeout is zero, each
+	// attempt of Exchange waits until the context is done, and each attempt of
+	// Request waits at most 5 seconds. If Retries is less than one, the packet
+	// is sent once.
 	Timeout time.Duration
 	Retries int
 }
@@ -66,8 +78,24 @@ func init() {
 }
 
 // Exchange sends the packet to the given server address and waits for a
-// response.
-func (c *Client) Exchange(packet *Packet, dst *net.UDPAddr, src *net.UDPAddr) (reply *Packet, err error) {
+// response with a matching identifier. The reply is parsed using the packet's
+// secret and dictionary, and is returned only if it authenticates against the
+// packet.
+//
+// The packet is sent up to c.Retries times, waiting at most c.Timeout for a
+// reply after each attempt. Replies that fail to parse, have a mismatched
+// identifier or are not authentic are discarded. ctx bounds the whole
+// exchange, including all retransmissions.
+func (c *Client) Exchange(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	dst, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.exchange(ctx, packet, dst, c.LocalAddr, c.Timeout)
+}
+
+func (c *Client) exchange(ctx context.Context, packet *Packet, dst *net.UDPAddr, src *net.UDPAddr, timeout time.Duration) (reply *Packet, err error) {
 	var (
 		wire []byte
 		conn *net.UDPConn
@@ -79,48 +107,114 @@ func (c *Client) Exchange(packet *Packet, dst *net.UDPAddr, src *net.UDPAddr) (r
 		return
 	}
 
+	// Replies are authenticated against the authenticator that was actually sent
+	request := *packet
+	copy(request.Authenticator[:], wire[4:20])
+
 	// If we weren't provided a src address - try default from context (which may be nil too)
 	if src == nil {
 		src = c.LocalAddr
 	}
 
-	if conn, err = net.DialUDP("udp4", src, dst); err != nil {
+	if conn, err = net.DialUDP("udp", src, dst); err != nil {
 		return
 	}
+	defer conn.Close()
+
+	// Unblock any pending read once the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	// The connection deadline may pass before ctx is marked done, so the
+	// context deadline is checked against the clock as well
+	ctxErr := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if ctxDeadline, ok := ctx.Deadline(); ok && !time.Now().Before(ctxDeadline) {
+			return context.DeadlineExceeded
+		}
+		return nil
+	}
+
+	retries := c.Retries
+	if retries < 1 {
+		retries = 1
+	}
+
+	for i := 0; i < retries; i++ {
+		// Each attempt is bounded by both timeout and the context deadline
+		var deadline time.Time
+		if timeout > 0 {
+			deadline = time.Now().Add(timeout)
+		}
+
+		if ctxDeadline, ok := ctx.Deadline(); ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+			deadline = ctxDeadline
+		}
+
+		conn.SetDeadline(deadline)
+		if err = ctxErr(); err != nil {
+			return nil, err
+		}
 
-	for i := 0; i < c.Retries; i++ {
-		conn.SetWriteDeadline(time.Now().Add(c.Timeout))
 		if _, err = conn.Write(wire); err != nil {
 			break
 		}
 
-		conn.SetReadDeadline(time.Now().Add(c.Timeout))
-		if n, err = conn.Read(buf[:]); err == nil {
-			if reply, err = Parse(buf[:n], packet.Secret, packet.Dictionary); err == nil {
-				if !reply.IsAuthentic(packet) {
-					err = errors.New("Non-authentic packet")
-				}
-
+		// Read until a valid reply arrives or the attempt times out
+		for {
+			if n, err = conn.Read(buf[:]); err != nil {
 				break
 			}
+
+			if reply, err = Parse(buf[:n], packet.Secret, packet.Dictionary); err != nil {
+				continue
+			}
+
+			if reply.Identifier != packet.Identifier || !reply.IsAuthentic(&request) {
+				continue
+			}
+
+			return reply, nil
+		}
+
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() || ctxErr() != nil {
+			break
 		}
 	}
 
-	conn.Close()
-	return
+	if cerr := ctxErr(); cerr != nil {
+		return nil, cerr
+	}
+
+	return nil, err
 }
 
-// Request send a RADIUS request
+// Request send a RADIUS request. If c.Timeout is zero, each attempt waits
+// at most 5 seconds for a reply.
 func (c *Client) Request(params *RequestParams, requestType Code, attrs ...*Attribute) (result *RequestResult) {
 	var (
 		reply *Packet
 	)
 
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+
 	result = &RequestResult{Timestamp: time.Now()}
 	p := New(requestType, params.Secret)
 	p.AddAttrs(attrs)
 
-	if reply, result.Error = c.Exchange(p, params.DstAddressPort, params.SrcAddress); result.Error == nil {
+	if reply, result.Error = c.exchange(context.Background(), p, params.DstAddressPort, params.SrcAddress, timeout); result.Error == nil {
 		switch reply.Code {
 		case CodeDisconnectACK, CodeCoAACK:
 			result.Success = true
@@ -0,0 +1,198 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var clientTestSecret = []byte("secret")
+
+// startResponder starts a local UDP server that calls reply for every received
+// request, numbered from 1, and sends back the packets it returns.
+func startResponder(t *testing.T, reply func(n int32, request *Packet) []*Packet) (addr string, received *int32) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	received = new(int32)
+	go func() {
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, remoteAddr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			request, err := Parse(buf[:n], clientTestSecret, Builtin)
+			if err != nil {
+				continue
+			}
+
+			for _, packet := range reply(atomic.AddInt32(received, 1), request) {
+				wire, err := packet.Encode()
+				if err != nil {
+					continue
+				}
+				conn.WriteToUDP(wire, remoteAddr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String(), received
+}
+
+// replyTo returns a packet replying to request with the given code.
+func replyTo(request *Packet, code Code) *Packet {
+	return &Packet{
+		Code:          code,
+		Identifier:    request.Identifier,
+		Authenticator: request.Authenticator,
+		Secret:        request.Secret,
+		Dictionary:    Builtin,
+	}
+}
+
+func newClientTestRequest() *Packet {
+	p := New(CodeAccessRequest, clientTestSecret)
+	p.Add("User-Name", "user")
+	p.Add("User-Password", "pass")
+	return p
+}
+
+func TestClientExchangeDiscardsInvalidReplies(t *testing.T) {
+	addr, _ := startResponder(t, func(n int32, request *Packet) []*Packet {
+		wrongIdentifier := replyTo(request, CodeAccessAccept)
+		wrongIdentifier.Identifier++
+		wrongIdentifier.Add("Reply-Message", "wrong identifier")
+
+		wrongSecret := replyTo(request, CodeAccessAccept)
+		wrongSecret.Secret = []byte("wrong")
+		wrongSecret.Add("Reply-Message", "wrong secret")
+
+		valid := replyTo(request, CodeAccessAccept)
+		valid.Add("Reply-Message", "valid")
+
+		return []*Packet{wrongIdentifier, wrongSecret, valid}
+	})
+
+	client := Client{Timeout: time.Second, Retries: 1}
+	reply, err := client.Exchange(context.Background(), newClientTestRequest(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message := reply.String("Reply-Message"); message != "valid" {
+		t.Fatalf("expected the valid reply, got %q", message)
+	}
+}
+
+func TestClientExchangeRetransmits(t *testing.T) {
+	const dropped = 2
+
+	addr, received := startResponder(t, func(n int32, request *Packet) []*Packet {
+		if n <= dropped {
+			return nil
+		}
+		return []*Packet{replyTo(request, CodeAccessAccept)}
+	})
+
+	client := Client{Timeout: 100 * time.Millisecond, Retries: dropped + 1}
+	reply, err := client.Exchange(context.Background(), newClientTestRequest(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Code != CodeAccessAccept {
+		t.Fatalf("expected Access-Accept, got %d", reply.Code)
+	}
+	if n := atomic.LoadInt32(received); n != dropped+1 {
+		t.Fatalf("expected %d requests, got %d", dropped+1, n)
+	}
+}
+
+func TestClientExchangeDeadline(t *testing.T) {
+	addr, _ := startResponder(t, func(n int32, request *Packet) []*Packet {
+		return nil
+	})
+
+	// The context deadline is shorter than Retries*Timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	client := Client{Timeout: time.Second, Retries: 5}
+	start := time.Now()
+	_, err := client.Exchange(ctx, newClientTestRequest(), addr)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected Exchange to return at the deadline, took %s", elapsed)
+	}
+}
+
+func TestClientExchangeCancel(t *testing.T) {
+	addr, received := startResponder(t, func(n int32, request *Packet) []*Packet {
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Cancel once the request was received, while Exchange is reading
+		for atomic.LoadInt32(received) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	client := Client{Timeout: 5 * time.Second, Retries: 1}
+	start := time.Now()
+	_, err := client.Exchange(ctx, newClientTestRequest(), addr)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected cancellation to unblock the read, took %s", elapsed)
+	}
+}
+
+func TestClientExchangeAccounting(t *testing.T) {
+	addr, _ := startResponder(t, func(n int32, request *Packet) []*Packet {
+		// request.Authenticator is the one calculated on the wire
+		return []*Packet{replyTo(request, CodeAccountingResponse)}
+	})
+
+	packet := New(CodeAccountingRequest, clientTestSecret)
+	packet.Add("Acct-Status-Type", uint32(1))
+	random := packet.Authenticator
+
+	client := Client{Timeout: time.Second, Retries: 1}
+	reply, err := client.Exchange(context.Background(), packet, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Code != CodeAccountingResponse {
+		t.Fatalf("expected Accounting-Response, got %d", reply.Code)
+	}
+	if packet.Authenticator != random {
+		t.Fatal("expected Exchange not to modify the request authenticator")
+	}
+}
+
+func TestClientExchangeNoRetries(t *testing.T) {
+	addr, received := startResponder(t, func(n int32, request *Packet) []*Packet {
+		return nil
+	})
+
+	client := Client{Timeout: 100 * time.Millisecond}
+	_, err := client.Exchange(context.Background(), newClientTestRequest(), addr)
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if n := atomic.LoadInt32(received); n != 1 {
+		t.Fatalf("expected exactly 1 request, got %d", n)
+	}
+}
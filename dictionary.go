@@ -2,6 +2,7 @@ package radius
 
 import (
 	"errors"
+	"strings"
 	"sync"
 )
 
@@ -16,17 +17,40 @@ func initDictionary() {
 }
 
 type dictEntry struct {
-	Type  byte
-	Name  string
-	Codec AttributeCodec
+	VendorID uint32
+	Type     byte
+	Name     string
+	Codec    AttributeCodec
 }
 
+type dictVendor struct {
+	Name             string
+	attributesByType [256]*dictEntry
+	attributesByName map[string]*dictEntry
+}
+
+// ResolveFunc resolves an attribute name to its vendor ID and type. vendorID
+// is zero for top-level attributes. An error is returned if the name cannot
+// be resolved.
+type ResolveFunc func(d *Dictionary, name string) (vendorID uint32, t byte, err error)
+
 // Dictionary stores mappings between attribute names and types and
 // AttributeCodecs.
 type Dictionary struct {
 	mu               sync.RWMutex
 	attributesByType [256]*dictEntry
 	attributesByName map[string]*dictEntry
+	vendors          map[uint32]*dictVendor
+	vendorsByName    map[string]uint32
+	resolver         ResolveFunc
+}
+
+// SetResolver sets the ResolveFunc used to resolve attribute names in Attr and
+// in the name based Packet methods. If resolver is nil, Resolve is used.
+func (d *Dictionary) SetResolver(resolver ResolveFunc) {
+	d.mu.Lock()
+	d.resolver = resolver
+	d.mu.Unlock()
 }
 
 // Register registers the AttributeCodec for the given attribute name and type.
@@ -57,21 +81,182 @@ func (d *Dictionary) MustRegister(name string, t byte, codec AttributeCodec) {
 	}
 }
 
-func (d *Dictionary) get(name string) (t byte, codec AttributeCodec, ok bool) {
+// RegisterVendor registers the name of the given vendor ID. The name can be
+// used to prefix the vendor's attribute names, e.g. "Cisco.Cisco-AVPair".
+//
+// Vendor-Specific attributes of a registered vendor are decoded into vendor
+// attributes by Parse, even before any attribute of the vendor is registered.
+func (d *Dictionary) RegisterVendor(name string, vendorID uint32) error {
+	if vendorID == 0 {
+		return errors.New("radius: vendor ID must not be zero")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.vendorsByName[name]; ok {
+		return errors.New("radius: vendor name already registered")
+	}
+	vendor := d.vendor(vendorID)
+	if vendor.Name != "" {
+		return errors.New("radius: vendor already registered")
+	}
+	vendor.Name = name
+	if d.vendorsByName == nil {
+		d.vendorsByName = make(map[string]uint32)
+	}
+	d.vendorsByName[name] = vendorID
+	return nil
+}
+
+// MustRegisterVendor is a helper for RegisterVendor that panics if it returns
+// an error.
+func (d *Dictionary) MustRegisterVendor(name string, vendorID uint32) {
+	if err := d.RegisterVendor(name, vendorID); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterVendorAttr registers the AttributeCodec for the given vendor
+// attribute name, vendor ID and vendor type. Vendor attributes are carried
+// inside Vendor-Specific (26) attributes, as described in RFC 2865 section
+// 5.26.
+//
+// Each vendor has its own attribute name space, so the same name may be
+// registered by several vendors, or by a vendor and the top-level dictionary.
+// See Resolve for how such names are resolved.
+func (d *Dictionary) RegisterVendorAttr(name string, vendorID uint32, t byte, codec AttributeCodec) error {
+	if vendorID == 0 {
+		return errors.New("radius: vendor ID must not be zero")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	vendor := d.vendor(vendorID)
+	if vendor.attributesByType[t] != nil {
+		return errors.New("radius: vendor attribute already registered")
+	}
+	if vendor.attributesByName[name] != nil {
+		return errors.New("radius: vendor attribute name already registered")
+	}
+	entry := &dictEntry{
+		VendorID: vendorID,
+		Type:     t,
+		Name:     name,
+		Codec:    codec,
+	}
+	vendor.attributesByType[t] = entry
+	if vendor.attributesByName == nil {
+		vendor.attributesByName = make(map[string]*dictEntry)
+	}
+	vendor.attributesByName[name] = entry
+	return nil
+}
+
+// MustRegisterVendorAttr is a helper for RegisterVendorAttr that panics if it
+// returns an error.
+func (d *Dictionary) MustRegisterVendorAttr(name string, vendorID uint32, t byte, codec AttributeCodec) {
+	if err := d.RegisterVendorAttr(name, vendorID, t, codec); err != nil {
+		panic(err)
+	}
+}
+
+// vendor returns the given vendor, creating it if needed. d.mu must be held
+// for writing.
+func (d *Dictionary) vendor(vendorID uint32) *dictVendor {
+	vendor := d.vendors[vendorID]
+	if vendor == nil {
+		vendor = &dictVendor{}
+		if d.vendors == nil {
+			d.vendors = make(map[uint32]*dictVendor)
+		}
+		d.vendors[vendorID] = vendor
+	}
+	return vendor
+}
+
+// Resolve is the default ResolveFunc. Names are resolved in the following
+// order:
+//
+//  - A top-level attribute registered under name
+//  - A vendor attribute, if name is prefixed with a registered vendor name
+//    and a dot, e.g. "Cisco.Cisco-AVPair"
+//  - A vendor attribute registered under name by exactly one vendor. If
+//    several vendors registered the name, an error is returned and the
+//    vendor-prefixed name has to be used
+func Resolve(d *Dictionary, name string) (vendorID uint32, t byte, err error) {
+	if t, ok := d.Type(name); ok {
+		return 0, t, nil
+	}
+
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if vendorID, ok := d.VendorID(name[:i]); ok {
+			if t, ok := d.VendorType(vendorID, name[i+1:]); ok {
+				return vendorID, t, nil
+			}
+		}
+	}
+
 	d.mu.RLock()
-	entry := d.attributesByName[name]
+	defer d.mu.RUnlock()
+	var found *dictEntry
+	for _, vendor := range d.vendors {
+		if entry := vendor.attributesByName[name]; entry != nil {
+			if found != nil {
+				return 0, 0, errors.New("radius: attribute name is registered by several vendors")
+			}
+			found = entry
+		}
+	}
+	if found == nil {
+		return 0, 0, errors.New("radius: attribute name not registered")
+	}
+	return found.VendorID, found.Type, nil
+}
+
+// resolve resolves the given name using the dictionary's ResolveFunc, and
+// returns the matching dictionary entry.
+func (d *Dictionary) resolve(name string) (*dictEntry, error) {
+	// The resolver may call back into d, so the lock is not held while it runs
+	d.mu.RLock()
+	resolver := d.resolver
 	d.mu.RUnlock()
+	if resolver == nil {
+		resolver = Resolve
+	}
+	vendorID, t, err := resolver(d, name)
+	if err != nil {
+		return nil, err
+	}
+	entry := d.entry(&Attribute{VendorID: vendorID, Type: t})
 	if entry == nil {
-		return
+		return nil, errors.New("radius: attribute name not registered")
 	}
-	t = entry.Type
-	codec = entry.Codec
-	ok = true
-	return
+	return entry, nil
+}
+
+// entry returns the dictionary entry describing the given attribute, or nil
+// if its type is not registered.
+func (d *Dictionary) entry(attr *Attribute) *dictEntry {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if attr.VendorID == 0 {
+		return d.attributesByType[attr.Type]
+	}
+	if vendor := d.vendors[attr.VendorID]; vendor != nil {
+		return vendor.attributesByType[attr.Type]
+	}
+	return nil
+}
+
+// hasVendor returns if the given vendor ID, or any of its attributes, is
+// registered.
+func (d *Dictionary) hasVendor(vendorID uint32) bool {
+	d.mu.RLock()
+	vendor := d.vendors[vendorID]
+	d.mu.RUnlock()
+	return vendor != nil
 }
 
 // Attr returns a new *Attribute whose type is registered under the given
-// name.
+// name. The name is resolved using the ResolveFunc given to SetResolver.
 //
 // If name is not registered, nil and an error is returned.
 //
@@ -79,11 +264,11 @@ func (d *Dictionary) get(name string) (t byte, codec AttributeCodec, ok bool) {
 // first transformed before being stored in *Attribute. If the transform
 // function returns an error, nil and the error is returned.
 func (d *Dictionary) Attr(name string, value interface{}) (*Attribute, error) {
-	t, codec, ok := d.get(name)
-	if !ok {
-		return nil, errors.New("radius: attribute name not registered")
+	entry, err := d.resolve(name)
+	if err != nil {
+		return nil, err
 	}
-	if transformer, ok := codec.(AttributeTransformer); ok {
+	if transformer, ok := entry.Codec.(AttributeTransformer); ok {
 		transformed, err := transformer.Transform(value)
 		if err != nil {
 			return nil, err
@@ -91,8 +276,9 @@ func (d *Dictionary) Attr(name string, value interface{}) (*Attribute, error) {
 		value = transformed
 	}
 	return &Attribute{
-		Type:  t,
-		Value: value,
+		VendorID: entry.VendorID,
+		Type:     entry.Type,
+		Value:    value,
 	}, nil
 }
 
@@ -119,8 +305,20 @@ func (d *Dictionary) Name(t byte) (name string, ok bool) {
 	return
 }
 
-// Type returns the registered type for the given attribute name. ok is false
-// if the given name is not registered.
+// VendorName returns the registered name for the given vendor ID and vendor
+// type. ok is false if the given vendor attribute is not registered.
+func (d *Dictionary) VendorName(vendorID uint32, t byte) (name string, ok bool) {
+	entry := d.entry(&Attribute{VendorID: vendorID, Type: t})
+	if entry == nil {
+		return
+	}
+	name = entry.Name
+	ok = true
+	return
+}
+
+// Type returns the registered type for the given top-level attribute name. ok
+// is false if the given name is not registered.
 func (d *Dictionary) Type(name string) (t byte, ok bool) {
 	d.mu.RLock()
 	entry := d.attributesByName[name]
@@ -133,6 +331,34 @@ func (d *Dictionary) Type(name string) (t byte, ok bool) {
 	return
 }
 
+// VendorID returns the vendor ID registered under the given vendor name. ok is
+// false if the given name is not registered.
+func (d *Dictionary) VendorID(name string) (vendorID uint32, ok bool) {
+	d.mu.RLock()
+	vendorID, ok = d.vendorsByName[name]
+	d.mu.RUnlock()
+	return
+}
+
+// VendorType returns the registered vendor type for the given vendor ID and
+// vendor attribute name. ok is false if the given name is not registered by
+// the vendor.
+func (d *Dictionary) VendorType(vendorID uint32, name string) (t byte, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	vendor := d.vendors[vendorID]
+	if vendor == nil {
+		return
+	}
+	entry := vendor.attributesByName[name]
+	if entry == nil {
+		return
+	}
+	t = entry.Type
+	ok = true
+	return
+}
+
 // Codec returns the AttributeCodec for the given registered type. nil is
 // returned if the given type is not registered.
 func (d *Dictionary) Codec(t byte) AttributeCodec {
@@ -144,3 +370,14 @@ func (d *Dictionary) Codec(t byte) AttributeCodec {
 	}
 	return entry.Codec
 }
+
+// VendorCodec returns the AttributeCodec for the given registered vendor ID
+// and vendor type. AttributeUnknown is returned if the given vendor attribute
+// is not registered.
+func (d *Dictionary) VendorCodec(vendorID uint32, t byte) AttributeCodec {
+	entry := d.entry(&Attribute{VendorID: vendorID, Type: t})
+	if entry == nil {
+		return AttributeUnknown
+	}
+	return entry.Codec
+}
@@ -0,0 +1,355 @@
+package radius
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maximum nesting of $INCLUDE directives
+const maxDictionaryIncludeDepth = 16
+
+// ParseDictionary parses a dictionary in the FreeRADIUS dictionary format. The
+// following subset of the format is supported:
+//
+//  ATTRIBUTE name type datatype [vendor|flags]
+//  VALUE attribute name number
+//  VENDOR name id [format=1,1]
+//  BEGIN-VENDOR name
+//  END-VENDOR name
+//
+// Supported data types are string, octets, integer, ipaddr and date. They are
+// decoded with AttributeText, AttributeString, AttributeInteger,
+// AttributeAddress and AttributeTime respectively. Attributes defined inside a
+// BEGIN-VENDOR/END-VENDOR block are registered as vendor attributes.
+//
+// The only supported ATTRIBUTE flag is encrypt=1 (User-Password encryption);
+// such attributes are decoded with AttributeString, as the value is not
+// decrypted. Other flags are rejected. Vendors must use the RFC 2865
+// sub-attribute format (format=1,1); other VENDOR formats and BEGIN-VENDOR
+// flags are rejected. Numbers are decimal, or hexadecimal when prefixed with
+// 0x.
+//
+// Integer attributes accept the names defined by VALUE entries wherever a
+// uint32 value is accepted, e.g. p.Add("Service-Type", "Framed-User").
+//
+// $INCLUDE directives are not supported by ParseDictionary; use
+// LoadDictionaryFile instead. If there is a problem parsing the dictionary,
+// nil and an error including the line number is returned.
+func ParseDictionary(r io.Reader) (*Dictionary, error) {
+	parser := dictParser{
+		dict: &Dictionary{},
+	}
+	if err := parser.parse(r, ""); err != nil {
+		return nil, err
+	}
+	return parser.dict, nil
+}
+
+// LoadDictionaryFile parses the FreeRADIUS dictionary file at the given path.
+// See ParseDictionary for the supported format. $INCLUDE directives are
+// resolved relative to the directory of the including file.
+func LoadDictionaryFile(path string) (*Dictionary, error) {
+	parser := dictParser{
+		dict:    &Dictionary{},
+		include: true,
+	}
+	if err := parser.parseFile(path); err != nil {
+		return nil, err
+	}
+	return parser.dict, nil
+}
+
+type dictParser struct {
+	dict    *Dictionary
+	include bool
+	depth   int
+}
+
+func (p *dictParser) parseFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return p.parse(f, path)
+}
+
+func (p *dictParser) parse(r io.Reader, path string) error {
+	var (
+		vendorID   uint32
+		vendorName string
+		line       int
+	)
+
+	errorf := func(format string, args ...interface{}) error {
+		if path != "" {
+			return fmt.Errorf("radius: dictionary %s line %d: %s", path, line, fmt.Sprintf(format, args...))
+		}
+		return fmt.Errorf("radius: dictionary line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i > -1 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "ATTRIBUTE":
+			if len(fields) < 4 {
+				return errorf("ATTRIBUTE requires a name, type and data type")
+			}
+			t, err := parseDictNumber(fields[2], 8)
+			if err != nil {
+				return errorf("invalid attribute type %q", fields[2])
+			}
+			codec, err := dictCodec(fields[3])
+			if err != nil {
+				return errorf("%s", err)
+			}
+
+			attrVendorID := vendorID
+			if len(fields) > 5 {
+				return errorf("too many fields for ATTRIBUTE")
+			}
+			if len(fields) == 5 {
+				// Old-style vendor attributes name their vendor after the data type
+				if id, ok := p.dict.VendorID(fields[4]); ok {
+					attrVendorID = id
+				} else if codec, err = dictFlags(fields[3], codec, fields[4]); err != nil {
+					return errorf("%s", err)
+				}
+			}
+
+			if attrVendorID != 0 {
+				err = p.dict.RegisterVendorAttr(fields[1], attrVendorID, byte(t), codec)
+			} else {
+				err = p.dict.Register(fields[1], byte(t), codec)
+			}
+			if err != nil {
+				return errorf("attribute %s: %s", fields[1], strings.TrimPrefix(err.Error(), "radius: "))
+			}
+
+		case "VALUE":
+			if len(fields) != 4 {
+				return errorf("VALUE requires an attribute, name and number")
+			}
+			number, err := parseDictNumber(fields[3], 32)
+			if err != nil {
+				return errorf("invalid value number %q", fields[3])
+			}
+
+			var entry *dictEntry
+			if t, ok := p.dict.VendorType(vendorID, fields[1]); ok {
+				entry = p.dict.entry(&Attribute{VendorID: vendorID, Type: t})
+			} else if entry, err = p.dict.resolve(fields[1]); err != nil {
+				return errorf("attribute %s: %s", fields[1], strings.TrimPrefix(err.Error(), "radius: "))
+			}
+			enum, ok := entry.Codec.(*attributeEnum)
+			if !ok {
+				return errorf("attribute %s is not an integer attribute", fields[1])
+			}
+			enum.add(fields[2], uint32(number))
+
+		case "VENDOR":
+			if len(fields) < 3 {
+				return errorf("VENDOR requires a name and id")
+			}
+			if len(fields) > 4 {
+				return errorf("too many fields for VENDOR")
+			}
+			// Only 1-byte types and lengths are supported
+			if len(fields) == 4 && fields[3] != "format=1,1" {
+				return errorf("unsupported vendor flag %q", fields[3])
+			}
+			id, err := parseDictNumber(fields[2], 32)
+			if err != nil {
+				return errorf("invalid vendor id %q", fields[2])
+			}
+			if err := p.dict.RegisterVendor(fields[1], uint32(id)); err != nil {
+				return errorf("vendor %s: %s", fields[1], strings.TrimPrefix(err.Error(), "radius: "))
+			}
+
+		case "BEGIN-VENDOR":
+			if len(fields) < 2 {
+				return errorf("BEGIN-VENDOR requires a vendor name")
+			}
+			if len(fields) > 2 {
+				return errorf("unsupported BEGIN-VENDOR flag %q", fields[2])
+			}
+			if vendorID != 0 {
+				return errorf("BEGIN-VENDOR %s inside BEGIN-VENDOR %s", fields[1], vendorName)
+			}
+			id, ok := p.dict.VendorID(fields[1])
+			if !ok {
+				return errorf("unknown vendor %s", fields[1])
+			}
+			vendorID, vendorName = id, fields[1]
+
+		case "END-VENDOR":
+			if len(fields) < 2 {
+				return errorf("END-VENDOR requires a vendor name")
+			}
+			if len(fields) > 2 {
+				return errorf("too many fields for END-VENDOR")
+			}
+			if vendorID == 0 || fields[1] != vendorName {
+				return errorf("END-VENDOR %s without matching BEGIN-VENDOR", fields[1])
+			}
+			vendorID, vendorName = 0, ""
+
+		case "$INCLUDE":
+			if len(fields) != 2 {
+				return errorf("$INCLUDE requires a file name")
+			}
+			if !p.include {
+				return errorf("$INCLUDE is not supported, use LoadDictionaryFile")
+			}
+			if p.depth >= maxDictionaryIncludeDepth {
+				return errorf("too many nested $INCLUDE directives")
+			}
+			includePath := fields[1]
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(filepath.Dir(path), includePath)
+			}
+			f, err := os.Open(includePath)
+			if err != nil {
+				return errorf("$INCLUDE %s: %s", fields[1], err)
+			}
+			p.depth++
+			err = p.parse(f, includePath)
+			p.depth--
+			f.Close()
+			if err != nil {
+				return err
+			}
+
+		default:
+			return errorf("unsupported keyword %s", fields[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		// The scanner stopped on the line following the last one read
+		line++
+		return errorf("%s", err)
+	}
+
+	if vendorID != 0 {
+		return errorf("BEGIN-VENDOR %s without matching END-VENDOR", vendorName)
+	}
+	return nil
+}
+
+// dictCodec returns the AttributeCodec for the given dictionary data type.
+func dictCodec(dataType string) (AttributeCodec, error) {
+	switch dataType {
+	case "string":
+		return AttributeText, nil
+	case "octets":
+		return AttributeString, nil
+	case "integer":
+		return &attributeEnum{}, nil
+	case "ipaddr":
+		return AttributeAddress, nil
+	case "date":
+		return AttributeTime, nil
+	}
+	return nil, fmt.Errorf("unsupported data type %q", dataType)
+}
+
+// dictFlags applies the given comma-separated ATTRIBUTE flags to the codec of
+// an attribute of the given data type.
+func dictFlags(dataType string, codec AttributeCodec, flags string) (AttributeCodec, error) {
+	for _, flag := range strings.Split(flags, ",") {
+		switch flag {
+		case "encrypt=0":
+		case "encrypt=1":
+			if dataType != "string" && dataType != "octets" {
+				return nil, fmt.Errorf("flag %s requires a string or octets data type", flag)
+			}
+			// Encrypted values are random bytes, which are not valid text
+			codec = AttributeString
+		default:
+			return nil, fmt.Errorf("unsupported flag %q", flag)
+		}
+	}
+	return codec, nil
+}
+
+// parseDictNumber parses a decimal, or 0x-prefixed hexadecimal, dictionary
+// number.
+func parseDictNumber(s string, bitSize int) (uint64, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return strconv.ParseUint(s[2:], 16, bitSize)
+	}
+	return strconv.ParseUint(s, 10, bitSize)
+}
+
+// attributeEnum is an integer attribute whose values may be referred to by
+// the names defined in a dictionary.
+type attributeEnum struct {
+	values map[string]uint32
+	names  map[uint32]string
+}
+
+func (e *attributeEnum) add(name string, value uint32) {
+	if e.values == nil {
+		e.values = make(map[string]uint32)
+		e.names = make(map[uint32]string)
+	}
+	e.values[name] = value
+	// The first name defined for a value is its canonical name
+	if _, ok := e.names[value]; !ok {
+		e.names[value] = name
+	}
+}
+
+func (e *attributeEnum) Decode(packet *Packet, value []byte) (interface{}, error) {
+	return AttributeInteger.Decode(packet, value)
+}
+
+func (e *attributeEnum) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	transformed, err := e.Transform(value)
+	if err != nil {
+		return nil, err
+	}
+	return AttributeInteger.Encode(packet, transformed)
+}
+
+func (e *attributeEnum) Transform(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case uint32:
+		return v, nil
+	case string:
+		if integer, ok := e.values[v]; ok {
+			return integer, nil
+		}
+		return nil, errors.New("radius: unknown integer attribute value name")
+	}
+	return nil, errors.New("radius: integer attribute must be uint32 or a value name")
+}
+
+func (e *attributeEnum) String(value interface{}) string {
+	integer, ok := value.(uint32)
+	if !ok {
+		return ""
+	}
+	if name, ok := e.names[integer]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(integer), 10)
+}
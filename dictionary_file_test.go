@@ -0,0 +1,239 @@
+package radius
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDictionary = `
+# Excerpt of FreeRADIUS dictionary.rfc2865
+ATTRIBUTE	User-Name				1	string
+ATTRIBUTE	User-Password				2	string	encrypt=1
+ATTRIBUTE	CHAP-Password				3	octets
+ATTRIBUTE	NAS-IP-Address				4	ipaddr
+ATTRIBUTE	NAS-Port				5	integer
+ATTRIBUTE	Service-Type				6	integer
+ATTRIBUTE	Vendor-Specific				26	octets
+ATTRIBUTE	Event-Timestamp				55	date
+
+#	User Types
+
+VALUE	Service-Type			Login-User		1
+VALUE	Service-Type			Framed-User		2
+VALUE	Service-Type			Callback-Login-User	3
+VALUE	Service-Type			Administrative-User	0x06
+
+# Excerpt of FreeRADIUS dictionary.cisco
+VENDOR		Cisco				9
+
+BEGIN-VENDOR	Cisco
+
+ATTRIBUTE	Cisco-AVPair				1	string
+ATTRIBUTE	Cisco-Multilink-ID			187	integer
+ATTRIBUTE	Cisco-Disconnect-Cause			195	integer
+
+VALUE	Cisco-Disconnect-Cause		Unknown			2
+VALUE	Cisco-Disconnect-Cause		Idle-Timeout		10
+
+END-VENDOR	Cisco
+`
+
+func TestParseDictionary(t *testing.T) {
+	d, err := ParseDictionary(strings.NewReader(testDictionary))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typ, ok := d.Type("Event-Timestamp"); !ok || typ != 55 {
+		t.Fatalf("expected Event-Timestamp to be type 55, got %d", typ)
+	}
+	if vendorID, ok := d.VendorID("Cisco"); !ok || vendorID != 9 {
+		t.Fatalf("expected Cisco to be vendor 9, got %d", vendorID)
+	}
+	if typ, ok := d.VendorType(9, "Cisco-Disconnect-Cause"); !ok || typ != 195 {
+		t.Fatalf("expected Cisco-Disconnect-Cause to be type 195, got %d", typ)
+	}
+}
+
+func TestDictionaryRoundTrip(t *testing.T) {
+	d, err := ParseDictionary(strings.NewReader(testDictionary))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New(CodeAccessRequest, []byte("secret"))
+	p.Dictionary = d
+	p.AddAttr(&Attribute{Type: 2, Value: []byte{0xff, 0x00, 0xfe, 0x80}})
+	for _, attr := range []struct {
+		Name  string
+		Value interface{}
+	}{
+		{"User-Name", "user"},
+		{"Service-Type", "Framed-User"},
+		{"NAS-IP-Address", net.IPv4(192, 0, 2, 1)},
+		{"NAS-Port", uint32(7)},
+		{"Event-Timestamp", time.Unix(1500000000, 0)},
+		{"Cisco-AVPair", "shell:priv-lvl=15"},
+		{"Cisco-Disconnect-Cause", "Idle-Timeout"},
+	} {
+		if err := p.Add(attr.Name, attr.Value); err != nil {
+			t.Fatalf("%s: %s", attr.Name, err)
+		}
+	}
+
+	if err := p.Add("Service-Type", "No-Such-Value"); err == nil {
+		t.Fatal("expected an error adding an unknown value name")
+	}
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The encrypted User-Password is not valid UTF-8
+	q, err := Parse(wire, p.Secret, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := q.Value("Service-Type"); value != uint32(2) {
+		t.Errorf("expected Service-Type 2, got %v", value)
+	}
+	if value := q.String("Service-Type"); value != "Framed-User" {
+		t.Errorf("expected Service-Type Framed-User, got %q", value)
+	}
+	if value := q.String("Cisco-Disconnect-Cause"); value != "Idle-Timeout" {
+		t.Errorf("expected Cisco-Disconnect-Cause Idle-Timeout, got %q", value)
+	}
+	if value := q.String("Cisco-AVPair"); value != "shell:priv-lvl=15" {
+		t.Errorf("unexpected Cisco-AVPair %q", value)
+	}
+	if value := q.Value("NAS-IP-Address").(net.IP); !value.Equal(net.IPv4(192, 0, 2, 1)) {
+		t.Errorf("unexpected NAS-IP-Address %s", value)
+	}
+	if value := q.Value("Event-Timestamp").(time.Time); value.Unix() != 1500000000 {
+		t.Errorf("unexpected Event-Timestamp %s", value)
+	}
+
+	if err := q.Set("Service-Type", "Administrative-User"); err != nil {
+		t.Fatal(err)
+	}
+	if value := q.Value("Service-Type"); value != uint32(6) {
+		t.Errorf("expected Service-Type 6 after Set, got %v", value)
+	}
+}
+
+func TestParseDictionaryNumbers(t *testing.T) {
+	d, err := ParseDictionary(strings.NewReader("ATTRIBUTE\tA\t010\tinteger\nATTRIBUTE\tB\t0x1a\tinteger\nVALUE\tA\tX\t08\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typ, ok := d.Type("A"); !ok || typ != 10 {
+		t.Errorf("expected A to be type 10, got %d", typ)
+	}
+	if typ, ok := d.Type("B"); !ok || typ != 26 {
+		t.Errorf("expected B to be type 26, got %d", typ)
+	}
+	attr, err := d.Attr("A", "X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr.Value != uint32(8) {
+		t.Errorf("expected X to be 8, got %v", attr.Value)
+	}
+}
+
+func TestParseDictionaryErrors(t *testing.T) {
+	tests := []struct {
+		Dictionary string
+		Error      string
+	}{
+		{"ATTRIBUTE\tA\t1\tstring\n\nATTRIBUTE\tB\t2\tipv6addr\n", "line 3: unsupported data type"},
+		{"ATTRIBUTE\tA\t256\tstring\n", "line 1: invalid attribute type"},
+		{"ATTRIBUTE\tA\t1\tstring\tencrypt=2\n", "line 1: unsupported flag"},
+		{"ATTRIBUTE\tA\t1\tstring\thas_tag\n", "line 1: unsupported flag"},
+		{"ATTRIBUTE\tA\t1\tinteger\tencrypt=1\n", "line 1: flag encrypt=1 requires"},
+		{"ATTRIBUTE\tA\t1\tstring\nATTRIBUTE\tB\t1\tstring\n", "line 2: attribute B"},
+		{"VALUE\tA\tX\t1\n", "line 1: attribute A"},
+		{"ATTRIBUTE\tA\t1\tstring\nVALUE\tA\tX\t1\n", "line 2: attribute A is not an integer attribute"},
+		{"BEGIN-VENDOR\tCisco\n", "line 1: unknown vendor"},
+		{"VENDOR\tCisco\t9\nBEGIN-VENDOR\tCisco\n", "line 2: BEGIN-VENDOR Cisco without matching END-VENDOR"},
+		{"END-VENDOR\tCisco\n", "line 1: END-VENDOR Cisco without matching BEGIN-VENDOR"},
+		{"VENDOR\tUSR\t429\tformat=4,0\n", "line 1: unsupported vendor flag"},
+		{"VENDOR\tLucent\t4846\tformat=2,1\n", "line 1: unsupported vendor flag"},
+		{"VENDOR\tStarent\t8164\tformat=2,2\n", "line 1: unsupported vendor flag"},
+		{"VENDOR\tX\t1\tformat=1,0\n", "line 1: unsupported vendor flag"},
+		{"VENDOR\tX\t1\tformat=1,1\tparent=Y\n", "line 1: too many fields for VENDOR"},
+		{"VENDOR\tX\t1\nBEGIN-VENDOR\tX\tformat=Extended-Vendor-Specific-1\n", "line 2: unsupported BEGIN-VENDOR flag"},
+		{"VENDOR\tX\t1\nBEGIN-VENDOR\tX\nEND-VENDOR\tX\textra\n", "line 3: too many fields for END-VENDOR"},
+		{"ATTRIBUTE\tA\t1\tstring\n# " + strings.Repeat("x", bufio.MaxScanTokenSize) + "\n", "line 2: bufio.Scanner: token too long"},
+		{"FLAGS\tinternal\n", "line 1: unsupported keyword"},
+		{"# comment\n$INCLUDE\tdictionary.cisco\n", "line 2: $INCLUDE is not supported"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseDictionary(strings.NewReader(tt.Dictionary))
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.Dictionary)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.Error) {
+			t.Errorf("%q: expected error containing %q, got %q", tt.Dictionary, tt.Error, err)
+		}
+	}
+}
+
+func TestLoadDictionaryFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dictionary":              "$INCLUDE dictionary.rfc2865\n$INCLUDE vendor/dictionary.cisco\n",
+		"dictionary.rfc2865":      "ATTRIBUTE\tUser-Name\t1\tstring\n",
+		"vendor/dictionary.cisco": "$INCLUDE ../dictionary.cisco.values\n",
+		"dictionary.cisco.values": "VENDOR\tCisco\t9\nBEGIN-VENDOR\tCisco\nATTRIBUTE\tCisco-AVPair\t1\tstring\nEND-VENDOR\tCisco\n",
+		"missing":                 "# comment\n$INCLUDE dictionary.missing\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := LoadDictionaryFile(filepath.Join(dir, "dictionary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Type("User-Name"); !ok {
+		t.Error("expected User-Name to be registered")
+	}
+	if _, ok := d.VendorType(9, "Cisco-AVPair"); !ok {
+		t.Error("expected Cisco-AVPair to be registered")
+	}
+
+	_, err = LoadDictionaryFile(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Fatal("expected an error including a missing file")
+	}
+	if !strings.Contains(err.Error(), "line 2: $INCLUDE dictionary.missing") {
+		t.Fatalf("expected error to name the including line, got %q", err)
+	}
+}
+
+func TestParseDictionaryVendorFormat(t *testing.T) {
+	d, err := ParseDictionary(strings.NewReader("VENDOR\tCisco\t9\tformat=1,1\nBEGIN-VENDOR\tCisco\nATTRIBUTE\tCisco-AVPair\t1\tstring\nEND-VENDOR\tCisco\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.VendorType(9, "Cisco-AVPair"); !ok {
+		t.Fatal("expected Cisco-AVPair to be registered")
+	}
+}
@@ -0,0 +1,177 @@
+package radius
+
+import (
+	"errors"
+	"testing"
+)
+
+func newVendorTestDictionary() *Dictionary {
+	d := &Dictionary{}
+	d.MustRegister("User-Name", 1, AttributeText)
+	d.MustRegisterVendor("Acme", 100)
+	d.MustRegisterVendor("Initech", 200)
+	d.MustRegisterVendorAttr("User-Name", 100, 1, AttributeText)
+	d.MustRegisterVendorAttr("Shared", 100, 2, AttributeText)
+	d.MustRegisterVendorAttr("Shared", 200, 2, AttributeText)
+	d.MustRegisterVendorAttr("Unique", 200, 3, AttributeText)
+	return d
+}
+
+func TestResolve(t *testing.T) {
+	d := newVendorTestDictionary()
+
+	tests := []struct {
+		Name     string
+		VendorID uint32
+		Type     byte
+		Err      bool
+	}{
+		// Top-level names take precedence over vendor names
+		{"User-Name", 0, 1, false},
+		{"Acme.User-Name", 100, 1, false},
+		// A name registered by several vendors is ambiguous
+		{"Shared", 0, 0, true},
+		{"Acme.Shared", 100, 2, false},
+		{"Initech.Shared", 200, 2, false},
+		// A name registered by a single vendor resolves without a prefix
+		{"Unique", 200, 3, false},
+		{"Initech.Unique", 200, 3, false},
+		{"Acme.Unique", 0, 0, true},
+		{"Unknown.Unique", 0, 0, true},
+		{"Missing", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		vendorID, typ, err := Resolve(d, tt.Name)
+		if tt.Err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %d/%d", tt.Name, vendorID, typ)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.Name, err)
+			continue
+		}
+		if vendorID != tt.VendorID || typ != tt.Type {
+			t.Errorf("%s: expected %d/%d, got %d/%d", tt.Name, tt.VendorID, tt.Type, vendorID, typ)
+		}
+	}
+}
+
+func TestPacketVendorNameCollisions(t *testing.T) {
+	d := newVendorTestDictionary()
+	p := &Packet{
+		Code:       CodeAccessRequest,
+		Secret:     []byte("secret"),
+		Dictionary: d,
+	}
+
+	if err := p.Add("Shared", "x"); err == nil {
+		t.Fatal("expected an error adding an ambiguous attribute name")
+	}
+	p.Add("User-Name", "top")
+	p.Add("Acme.User-Name", "acme")
+	p.Add("Acme.Shared", "acme")
+	p.Add("Initech.Shared", "initech")
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Parse(wire, p.Secret, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"User-Name":      "top",
+		"Acme.User-Name": "acme",
+		"Acme.Shared":    "acme",
+		"Initech.Shared": "initech",
+	} {
+		if value := q.String(name); value != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, value)
+		}
+	}
+
+	if err := q.Set("Initech.Shared", "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if value := q.String("Acme.Shared"); value != "acme" {
+		t.Errorf("Set changed another vendor's attribute: %q", value)
+	}
+	if value := q.String("Initech.Shared"); value != "changed" {
+		t.Errorf("expected Set to change Initech.Shared, got %q", value)
+	}
+}
+
+func TestDictionaryResolver(t *testing.T) {
+	d := newVendorTestDictionary()
+
+	// Prefer Acme attributes over everything else
+	d.SetResolver(func(d *Dictionary, name string) (uint32, byte, error) {
+		if t, ok := d.VendorType(100, name); ok {
+			return 100, t, nil
+		}
+		return 0, 0, errors.New("radius: attribute name not registered")
+	})
+
+	attr, err := d.Attr("Shared", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr.VendorID != 100 || attr.Type != 2 {
+		t.Fatalf("expected resolver to pick Acme, got %d/%d", attr.VendorID, attr.Type)
+	}
+
+	p := &Packet{
+		Code:       CodeAccessRequest,
+		Secret:     []byte("secret"),
+		Dictionary: d,
+	}
+	p.AddAttr(&Attribute{Type: 1, Value: "top"})
+	p.AddAttr(&Attribute{VendorID: 100, Type: 1, Value: "acme"})
+
+	if values := p.Values("User-Name"); len(values) != 1 || values[0] != "acme" {
+		t.Fatalf("expected Values to use resolver, got %v", values)
+	}
+
+	if err := p.Set("User-Name", "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Attributes[0].Value != "top" || p.Attributes[1].Value != "changed" {
+		t.Fatalf("expected Set to use resolver, got %v, %v", p.Attributes[0].Value, p.Attributes[1].Value)
+	}
+
+	if _, err := d.Attr("Unique", "x"); err == nil {
+		t.Fatal("expected resolver error to be returned by Attr")
+	}
+}
+
+func TestDictionarySetResolverConcurrent(t *testing.T) {
+	d := newVendorTestDictionary()
+	p := &Packet{
+		Code:       CodeAccessRequest,
+		Secret:     []byte("secret"),
+		Dictionary: d,
+	}
+	p.Add("User-Name", "user")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			d.SetResolver(Resolve)
+			d.SetResolver(nil)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if value := p.String("User-Name"); value != "user" {
+			t.Fatalf("expected User-Name, got %q", value)
+		}
+	}
+	<-done
+}
@@ -64,4 +64,14 @@
 //  Acct-Terminate-Cause   49  uint32
 //  Acct-Multi-Session-Id  50  string
 //  Acct-Link-Count        51  uint32
+//
+// Vendor attributes are carried inside Vendor-Specific attributes. Once
+// registered with Dictionary.RegisterVendorAttr, they are decoded by Parse and
+// can be accessed by name like any other attribute. Top-level names take
+// precedence over vendor names; a vendor attribute can always be addressed by
+// prefixing its name with the vendor name, e.g. "Cisco.Cisco-AVPair". The
+// following vendor attributes are registered (name, vendor ID, vendor type, Go
+// data type):
+//
+//  Cisco-AVPair  9  1  []byte
 package radius
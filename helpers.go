@@ -124,10 +124,24 @@ func DecodeAVPairs(p *Packet) (avps []*AVP, err error) {
 		Value    []byte
 	)
 
-	for _, vsa := range p.Values("Vendor-Specific") {
-		if VendorID, TypeID, Value, err = DecodeAVPairByte(vsa.([]byte)); err != nil {
-			avps = nil
-			return
+	for _, attr := range p.Attributes {
+		switch {
+		case attr.VendorID != 0:
+			// Vendor attributes decoded by Parse are encoded back to raw values
+			VendorID, TypeID = attr.VendorID, attr.Type
+			if Value, err = p.attrCodec(attr).Encode(p, attr.Value); err != nil {
+				avps = nil
+				return
+			}
+
+		case attr.Type == AttrVendorSpecific:
+			if VendorID, TypeID, Value, err = DecodeAVPairByte(attr.Value.([]byte)); err != nil {
+				avps = nil
+				return
+			}
+
+		default:
+			continue
 		}
 
 		avps = append(avps,
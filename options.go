@@ -9,6 +9,13 @@ var WithDefaultPacketParser = func (s *Server) {
 	s.PacketParser = Parse
 }
 
+// WithStrictPacketParser makes the server drop packets that do not pass
+// Packet.Validate. Servers handling EAP or State-only Access-Requests
+// must not use it.
+var WithStrictPacketParser = func(s *Server) {
+	s.PacketParser = ParseStrict
+}
+
 
 func WithPacketParser(h ParseFunc)  ServerOption {
 	return func(s *Server) {
@@ -6,11 +6,16 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"errors"
+	"fmt"
 )
 
 // maximum RADIUS packet size
 const maxPacketSize = 4095
 
+// maximum vendor attribute value size: 253 bytes of Vendor-Specific payload
+// minus Vendor-Id (4), Vendor-Type (1) and Vendor-Length (1)
+const maxVendorAttrSize = 253 - 6
+
 // Code specifies the kind of RADIUS packet
 type Code byte
 
@@ -80,7 +85,8 @@ type ParseFunc func (data, secret []byte, dictionary *Dictionary) (*Packet, erro
 //
 // Note: this function does not validate the authenticity of a packet.
 // Ensuring a packet's authenticity should be done using the IsAuthentic
-// method.
+// method. Neither does it check that the packet contains the attributes
+// required by its code; see ParseStrict and the Validate method.
 func Parse(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
 	if len(data) < 20 {
 		return nil, errors.New("radius: packet must be at least 20 bytes long")
@@ -99,6 +105,12 @@ func Parse(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
 		return nil, errors.New("radius: invalid packet length")
 	}
 
+	// Octets following the packet length are padding and are ignored
+	if int(length) > len(data) {
+		return nil, errors.New("radius: packet is shorter than its length")
+	}
+	data = data[:length]
+
 	copy(packet.Authenticator[:], data[4:20])
 
 	// Attributes
@@ -109,13 +121,27 @@ func Parse(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
 		}
 
 		attrLength := attributes[1]
-		if attrLength < 1 || attrLength > 253 || len(attributes) < int(attrLength) {
+		if attrLength < 2 || len(attributes) < int(attrLength) {
 			return nil, errors.New("radius: invalid attribute length")
 		}
 
 		attrType := attributes[0]
 		attrValue := attributes[2:attrLength]
 
+		// Vendor-Specific attributes of registered vendors are split into
+		// their vendor attributes. The sub-attribute format is only
+		// recommended by RFC 2865, so ones that do not follow it are kept as
+		// opaque Vendor-Specific attributes.
+		if attrType == AttrVendorSpecific && len(attrValue) > 4 {
+			if vendorID := binary.BigEndian.Uint32(attrValue[0:4]); dictionary.hasVendor(vendorID) {
+				if vendorAttrs, err := parseVendorSpecific(packet, dictionary, vendorID, attrValue[4:]); err == nil {
+					packet.Attributes = append(packet.Attributes, vendorAttrs...)
+					attributes = attributes[attrLength:]
+					continue
+				}
+			}
+		}
+
 		codec := dictionary.Codec(attrType)
 		decoded, err := codec.Decode(packet, attrValue)
 		if err != nil {
@@ -131,10 +157,59 @@ func Parse(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
 		attributes = attributes[attrLength:]
 	}
 
-	// TODO: validate that the given packet (by code) has all the required attributes, etc.
 	return packet, nil
 }
 
+// ParseStrict is like Parse, but additionally returns an error if the parsed
+// packet does not pass Validate. It can be used as a Server's PacketParser to
+// reject malformed requests up front. Note that Validate rejects EAP and
+// State-only Access-Requests.
+func ParseStrict(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
+	packet, err := Parse(data, secret, dictionary)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = packet.Validate(); err != nil {
+		return nil, err
+	}
+
+	return packet, nil
+}
+
+// parseVendorSpecific decodes the vendor attributes packed in the value of a
+// Vendor-Specific attribute, following the Vendor-Id field.
+func parseVendorSpecific(packet *Packet, dictionary *Dictionary, vendorID uint32, data []byte) ([]*Attribute, error) {
+	var attrs []*Attribute
+
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errors.New("radius: vendor attribute must be at least 2 bytes long")
+		}
+
+		vendorLength := data[1]
+		if vendorLength < 2 || len(data) < int(vendorLength) {
+			return nil, errors.New("radius: invalid vendor attribute length")
+		}
+
+		vendorType := data[0]
+		codec := dictionary.VendorCodec(vendorID, vendorType)
+		decoded, err := codec.Decode(packet, data[2:vendorLength])
+		if err != nil {
+			return nil, err
+		}
+
+		attrs = append(attrs, &Attribute{
+			VendorID: vendorID,
+			Type:     vendorType,
+			Value:    decoded,
+		})
+		data = data[vendorLength:]
+	}
+
+	return attrs, nil
+}
+
 // IsAuthentic returns if the packet is an authenticate response to the given
 // request packet. Calling this function is only valid if both:
 //  - p.code is one of:
@@ -146,12 +221,24 @@ func Parse(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
 // 		CodeCoAACK, CodeCOANAK
 //  	CodeDisconnectACK, CodeDisconnectNAK
 //  - p.Authenticator contains the calculated authenticator
+//
+// If p was returned by Parse, the authenticator is calculated over its raw
+// wire data, up to the length given in the packet header.
 func (p *Packet) IsAuthentic(request *Packet) bool {
 	switch p.Code {
-	case CodeAccessAccept, CodeAccessReject, CodeAccountingRequest, CodeAccessChallenge, CodeCoAACK, CodeCoANAK, CodeDisconnectACK, CodeDisconnectNAK:
-		wire, err := p.Encode()
-		if err != nil {
-			return false
+	case CodeAccessAccept, CodeAccessReject, CodeAccountingRequest, CodeAccountingResponse, CodeAccessChallenge, CodeCoAACK, CodeCoANAK, CodeDisconnectACK, CodeDisconnectNAK:
+		var wire []byte
+		if p.Raw != nil && len(*p.Raw) >= 20 {
+			wire = *p.Raw
+			// Data following the packet length is padding and must be ignored
+			if length := int(binary.BigEndian.Uint16(wire[2:4])); length >= 20 && length < len(wire) {
+				wire = wire[:length]
+			}
+		} else {
+			var err error
+			if wire, err = p.Encode(); err != nil {
+				return false
+			}
 		}
 
 		hash := md5.New()
@@ -210,6 +297,67 @@ func (p *Packet) ResponseAuthenticator() (sum []byte, err error) {
 	return
 }
 
+// Validate checks that the packet contains the attributes required by its
+// code. The checks are this library's own policy, and are stricter than RFC
+// 2865 and RFC 2866:
+//  - Any packet contains at most one Message-Authenticator
+//  - Access-Request contains exactly one User-Name, and exactly one of
+//    User-Password or CHAP-Password
+//  - Accounting-Request contains exactly one Acct-Status-Type
+//
+// In particular, RFC 2865 only recommends User-Name, and permits an
+// Access-Request carrying State instead of a password. EAP Access-Requests
+// (RFC 3579), which carry EAP-Message and no password, are rejected.
+//
+// An error naming the offending attribute is returned if the packet is not
+// valid.
+func (p *Packet) Validate() error {
+	if n := p.count(AttrMessageAuthenticator); n > 1 {
+		return fmt.Errorf("radius: packet must contain at most one Message-Authenticator attribute, found %d", n)
+	}
+
+	switch p.Code {
+	case CodeAccessRequest:
+		if n := p.count(AttrUserName); n != 1 {
+			return fmt.Errorf("radius: Access-Request must contain exactly one User-Name attribute, found %d", n)
+		}
+
+		password, chap := p.count(AttrUserPassword), p.count(AttrCHAPPassword)
+		if password > 0 && chap > 0 {
+			return errors.New("radius: Access-Request must not contain both User-Password and CHAP-Password attributes")
+		}
+
+		if password > 1 {
+			return fmt.Errorf("radius: Access-Request must contain exactly one User-Password attribute, found %d", password)
+		}
+
+		if chap > 1 {
+			return fmt.Errorf("radius: Access-Request must contain exactly one CHAP-Password attribute, found %d", chap)
+		}
+
+		if password == 0 && chap == 0 {
+			return errors.New("radius: Access-Request must contain a User-Password or CHAP-Password attribute")
+		}
+
+	case CodeAccountingRequest:
+		if n := p.count(AttrAcctStatusType); n != 1 {
+			return fmt.Errorf("radius: Accounting-Request must contain exactly one Acct-Status-Type attribute, found %d", n)
+		}
+	}
+
+	return nil
+}
+
+// count returns the number of top-level attributes of the given type.
+func (p *Packet) count(t byte) (n int) {
+	for _, attr := range p.Attributes {
+		if attr.VendorID == 0 && attr.Type == t {
+			n++
+		}
+	}
+	return
+}
+
 // ClearAttributes removes all of the packet's attributes.
 func (p *Packet) ClearAttributes() {
 	p.Attributes = nil
@@ -227,8 +375,12 @@ func (p *Packet) Value(name string) interface{} {
 // Attr returns the first attribute whose dictionary name matches the given
 // name. nil is returned if no such attribute exists.
 func (p *Packet) Attr(name string) *Attribute {
+	entry, err := p.Dictionary.resolve(name)
+	if err != nil {
+		return nil
+	}
 	for _, attr := range p.Attributes {
-		if attrName, ok := p.Dictionary.Name(attr.Type); ok && attrName == name {
+		if attr.VendorID == entry.VendorID && attr.Type == entry.Type {
 			return attr
 		}
 	}
@@ -237,8 +389,12 @@ func (p *Packet) Attr(name string) *Attribute {
 
 // Values returns a slice of all attributes' values with given name
 func (p *Packet) Values(name string) (values []interface{}) {
+	entry, err := p.Dictionary.resolve(name)
+	if err != nil {
+		return
+	}
 	for _, attr := range p.Attributes {
-		if attrName, ok := p.Dictionary.Name(attr.Type); ok && attrName == name {
+		if attr.VendorID == entry.VendorID && attr.Type == entry.Type {
 			values = append(values, attr.Value)
 		}
 	}
@@ -264,7 +420,7 @@ func (p *Packet) String(name string) string {
 	}
 	value := attr.Value
 
-	if codec := p.Dictionary.Codec(attr.Type); codec != nil {
+	if codec := p.attrCodec(attr); codec != nil {
 		if stringer, ok := codec.(AttributeStringer); ok {
 			return stringer.String(value)
 		}
@@ -286,6 +442,14 @@ func (p *Packet) String(name string) string {
 	return ""
 }
 
+// attrCodec returns the AttributeCodec of the given attribute.
+func (p *Packet) attrCodec(attr *Attribute) AttributeCodec {
+	if attr.VendorID != 0 {
+		return p.Dictionary.VendorCodec(attr.VendorID, attr.Type)
+	}
+	return p.Dictionary.Codec(attr.Type)
+}
+
 // Add adds an attribute whose dictionary name matches the given name.
 func (p *Packet) Add(name string, value interface{}) error {
 	attr, err := p.Dictionary.Attr(name, value)
@@ -311,9 +475,13 @@ func (p *Packet) AddAttrs(attributes []*Attribute) {
 // Set sets the value of the first attribute whose dictionary name matches the
 // given name. If no such attribute exists, a new attribute is added
 func (p *Packet) Set(name string, value interface{}) error {
+	entry, err := p.Dictionary.resolve(name)
+	if err != nil {
+		return err
+	}
 	for _, attr := range p.Attributes {
-		if attrName, ok := p.Dictionary.Name(attr.Type); ok && attrName == name {
-			codec := p.Dictionary.Codec(attr.Type)
+		if attr.VendorID == entry.VendorID && attr.Type == entry.Type {
+			codec := entry.Codec
 			if transformer, ok := codec.(AttributeTransformer); ok {
 				transformed, err := transformer.Transform(value)
 				if err != nil {
@@ -367,12 +535,27 @@ func (p *Packet) Encode() ([]byte, error) {
 	var bufferAttrs bytes.Buffer
 
 	for _, attr := range p.Attributes {
-		codec := p.Dictionary.Codec(attr.Type)
+		codec := p.attrCodec(attr)
 		wire, err := codec.Encode(p, attr.Value)
 		if err != nil {
 			return nil, err
 		}
 
+		// Vendor attributes are each framed in their own Vendor-Specific attribute
+		if attr.VendorID != 0 {
+			if len(wire) > maxVendorAttrSize {
+				return nil, errors.New("radius: encoded vendor attribute is too long")
+			}
+
+			bufferAttrs.WriteByte(AttrVendorSpecific)
+			bufferAttrs.WriteByte(byte(len(wire) + 8))
+			binary.Write(&bufferAttrs, binary.BigEndian, attr.VendorID)
+			bufferAttrs.WriteByte(attr.Type)
+			bufferAttrs.WriteByte(byte(len(wire) + 2))
+			bufferAttrs.Write(wire)
+			continue
+		}
+
 		if len(wire) > 253 {
 			return nil, errors.New("radius: encoded attribute is too long")
 		}
@@ -0,0 +1,232 @@
+package radius
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPacketVendorAttributes(t *testing.T) {
+	p := New(CodeAccessAccept, []byte("secret"))
+	p.Add("Cisco-AVPair", "a=b")
+	p.Add("User-Name", "user")
+	p.Add("Cisco-AVPair", "c=d")
+	p.AddAttr(&Attribute{
+		Type:  AttrVendorSpecific,
+		Value: EncodeAVPair(VendJuniper, 5, "x"),
+	})
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Parse(wire, p.Secret, Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := q.Values("Cisco-AVPair")
+	if len(values) != 2 || string(values[0].([]byte)) != "a=b" || string(values[1].([]byte)) != "c=d" {
+		t.Fatalf("unexpected Cisco-AVPair values: %v", values)
+	}
+
+	// Unregistered vendors are kept as opaque Vendor-Specific attributes
+	if vsas := q.Values("Vendor-Specific"); len(vsas) != 1 {
+		t.Fatalf("expected 1 Vendor-Specific attribute, got %d", len(vsas))
+	}
+
+	avps, err := DecodeAVPairs(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(avps) != 3 {
+		t.Fatalf("expected 3 AVPs, got %d", len(avps))
+	}
+}
+
+func TestParsePackedVendorSpecific(t *testing.T) {
+	wire := []byte{
+		byte(CodeAccessRequest), 1, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		// Two Cisco sub-attributes in one Vendor-Specific attribute
+		AttrVendorSpecific, 16, 0, 0, 0, VendCisco, 1, 5, 'a', '=', 'b', 1, 5, 'c', '=', 'd',
+	}
+	wire[3] = byte(len(wire))
+
+	p, err := Parse(wire, []byte("secret"), Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values := p.Values("Cisco-AVPair"); len(values) != 2 {
+		t.Fatalf("expected 2 Cisco-AVPair values, got %v", values)
+	}
+}
+
+func TestParseMalformedVendorSpecific(t *testing.T) {
+	// The sub-attribute length exceeds the Vendor-Specific attribute
+	vsa := []byte{0, 0, 0, VendCisco, 1, 9, 'x'}
+	wire := []byte{
+		byte(CodeAccessRequest), 1, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		AttrVendorSpecific, byte(len(vsa) + 2),
+	}
+	wire = append(wire, vsa...)
+	wire[3] = byte(len(wire))
+
+	p, err := Parse(wire, []byte("secret"), Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, ok := p.Value("Vendor-Specific").([]byte)
+	if !ok || !bytes.Equal(raw, vsa) {
+		t.Fatalf("expected opaque Vendor-Specific value %v, got %v", vsa, p.Value("Vendor-Specific"))
+	}
+}
+
+func TestEncodeVendorAttributeTooLong(t *testing.T) {
+	p := New(CodeAccessRequest, []byte("secret"))
+	if err := p.Add("Cisco-AVPair", make([]byte, maxVendorAttrSize+1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Encode(); err == nil {
+		t.Fatal("expected an error encoding an oversized vendor attribute")
+	}
+}
+
+func TestVendorAttributeMaxSize(t *testing.T) {
+	value := bytes.Repeat([]byte{'x'}, maxVendorAttrSize)
+
+	p := New(CodeAccessRequest, []byte("secret"))
+	if err := p.Add("Cisco-AVPair", value); err != nil {
+		t.Fatal(err)
+	}
+	p.Add("User-Name", "user")
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wire[21] != 255 {
+		t.Fatalf("expected Vendor-Specific length 255, got %d", wire[21])
+	}
+
+	q, err := Parse(wire, p.Secret, Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, ok := q.Value("Cisco-AVPair").([]byte); !ok || !bytes.Equal(raw, value) {
+		t.Fatalf("Cisco-AVPair did not round-trip: %v", q.Value("Cisco-AVPair"))
+	}
+	if name := q.String("User-Name"); name != "user" {
+		t.Fatalf("expected User-Name after the Vendor-Specific attribute, got %q", name)
+	}
+}
+
+func TestParseInvalidAttributeLength(t *testing.T) {
+	for _, attrLength := range []byte{0, 1, 4} {
+		wire := []byte{
+			byte(CodeAccessRequest), 1, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			AttrUserName, attrLength, 'x',
+		}
+		wire[3] = byte(len(wire))
+
+		if _, err := Parse(wire, []byte("secret"), Builtin); err == nil {
+			t.Errorf("attribute length %d: expected an error", attrLength)
+		}
+	}
+}
+
+func TestIsAuthenticPadding(t *testing.T) {
+	request := New(CodeAccessRequest, []byte("secret"))
+	reply := &Packet{
+		Code:          CodeAccessAccept,
+		Identifier:    request.Identifier,
+		Authenticator: request.Authenticator,
+		Secret:        request.Secret,
+		Dictionary:    Builtin,
+	}
+	reply.Add("Reply-Message", "hello")
+
+	wire, err := reply.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Padding following the packet length must be ignored
+	wire = append(wire, 0, 0, 0)
+
+	p, err := Parse(wire, request.Secret, Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.IsAuthentic(request) {
+		t.Fatal("expected padded reply to be authentic")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type attr struct {
+		Name  string
+		Value interface{}
+	}
+
+	tests := []struct {
+		Code  Code
+		Attrs []attr
+		Error string
+	}{
+		{CodeAccessRequest, []attr{{"User-Name", "user"}, {"User-Password", "pass"}}, ""},
+		{CodeAccessRequest, []attr{{"User-Name", "user"}, {"CHAP-Password", "chap"}}, ""},
+		{CodeAccessRequest, []attr{{"User-Password", "pass"}}, "exactly one User-Name attribute, found 0"},
+		{CodeAccessRequest, []attr{{"User-Name", "user"}, {"User-Name", "user"}, {"User-Password", "pass"}}, "exactly one User-Name attribute, found 2"},
+		{CodeAccessRequest, []attr{{"User-Name", "user"}, {"User-Password", "pass"}, {"CHAP-Password", "chap"}}, "both User-Password and CHAP-Password"},
+		{CodeAccessRequest, []attr{{"User-Name", "user"}}, "a User-Password or CHAP-Password attribute"},
+		{CodeAccessRequest, []attr{{"User-Name", "user"}, {"User-Password", "pass"}, {"User-Password", "pass"}}, "exactly one User-Password attribute, found 2"},
+		{CodeAccessRequest, []attr{{"User-Name", "user"}, {"CHAP-Password", "chap"}, {"CHAP-Password", "chap"}}, "exactly one CHAP-Password attribute, found 2"},
+		{CodeAccountingRequest, []attr{{"Acct-Status-Type", uint32(1)}}, ""},
+		{CodeAccountingRequest, nil, "exactly one Acct-Status-Type attribute, found 0"},
+		{CodeAccountingRequest, []attr{{"Acct-Status-Type", uint32(1)}, {"Acct-Status-Type", uint32(2)}}, "exactly one Acct-Status-Type attribute, found 2"},
+		{CodeAccessAccept, []attr{{"Message-Authenticator", "a"}}, ""},
+		{CodeAccessAccept, []attr{{"Message-Authenticator", "a"}, {"Message-Authenticator", "b"}}, "at most one Message-Authenticator attribute, found 2"},
+		{CodeAccessRequest, []attr{{"User-Name", "user"}, {"User-Password", "pass"}, {"Message-Authenticator", "a"}, {"Message-Authenticator", "b"}}, "at most one Message-Authenticator attribute, found 2"},
+	}
+
+	for i, tt := range tests {
+		p := New(tt.Code, []byte("secret"))
+		for _, a := range tt.Attrs {
+			if err := p.Add(a.Name, a.Value); err != nil {
+				t.Fatalf("#%d: %s", i, err)
+			}
+		}
+
+		wire, err := p.Encode()
+		if err != nil {
+			t.Fatalf("#%d: %s", i, err)
+		}
+
+		// Parse stays lenient
+		if _, err := Parse(wire, p.Secret, Builtin); err != nil {
+			t.Errorf("#%d: Parse: unexpected error: %s", i, err)
+		}
+
+		errValidate := p.Validate()
+		_, errStrict := ParseStrict(wire, p.Secret, Builtin)
+
+		for name, err := range map[string]error{"Validate": errValidate, "ParseStrict": errStrict} {
+			switch {
+			case tt.Error == "" && err != nil:
+				t.Errorf("#%d: %s: unexpected error: %s", i, name, err)
+			case tt.Error != "" && err == nil:
+				t.Errorf("#%d: %s: expected error containing %q", i, name, tt.Error)
+			case tt.Error != "" && !strings.Contains(err.Error(), tt.Error):
+				t.Errorf("#%d: %s: expected error containing %q, got %q", i, name, tt.Error, err)
+			}
+		}
+	}
+}
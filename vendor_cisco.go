@@ -0,0 +1,7 @@
+package radius
+
+func init() {
+	builtinOnce.Do(initDictionary)
+	Builtin.MustRegisterVendor("Cisco", VendCisco)
+	Builtin.MustRegisterVendorAttr("Cisco-AVPair", VendCisco, 1, AttributeString)
+}